@@ -0,0 +1,45 @@
+package main
+
+import "fmt"
+
+// Array describes a job array: Start-End[:Step], optionally throttled to
+// MaxConcurrent simultaneously running tasks.
+type Array struct {
+	Start         int
+	End           int
+	Step          int
+	MaxConcurrent int
+}
+
+// arrayIndexVar is the shell variable each batch system exposes as the
+// running task's index within the array.
+var arrayIndexVar = map[string]string{
+	BatchPBS:   "$PBS_ARRAY_INDEX",
+	BatchSlurm: "$SLURM_ARRAY_TASK_ID",
+}
+
+// arrayDirective renders the header line requesting a job array, or "", nil
+// if array is unset. Returns an error if array is set but batchSystem has no
+// way to express it, rather than silently submitting a non-array job.
+func arrayDirective(batchSystem string, array Array) (string, error) {
+	if array.End == 0 {
+		return "", nil
+	}
+
+	spec := fmt.Sprintf("%d-%d", array.Start, array.End)
+	if array.Step > 0 {
+		spec += fmt.Sprintf(":%d", array.Step)
+	}
+	if array.MaxConcurrent > 0 {
+		spec += fmt.Sprintf("%%%d", array.MaxConcurrent)
+	}
+
+	switch batchSystem {
+	case BatchPBS:
+		return fmt.Sprintf("#PBS -J %s\n", spec), nil
+	case BatchSlurm:
+		return fmt.Sprintf("#SBATCH --array=%s\n", spec), nil
+	default:
+		return "", fmt.Errorf("job arrays are not supported on batch system %q", batchSystem)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// BatchBackend describes how to generate a job script header for a batch
+// system, how to submit it, and how to tell whether that system is present
+// on the current machine.
+type BatchBackend interface {
+	// Name is the backend's identifier, as passed to the "-b" flag and
+	// stored in JobRecord.BatchSystem.
+	Name() string
+	// Detect reports whether this backend's tooling is available.
+	Detect() bool
+	// WriteHeader writes the backend-specific job preamble to builder.
+	WriteHeader(builder *strings.Builder, config ExtendedConfig) error
+	// SubmitCommand returns the command and arguments used to submit the
+	// script at scriptPath.
+	SubmitCommand(scriptPath string) []string
+	// Manifest reports whether WriteHeader produces a complete, self
+	// contained job description (e.g. a Kubernetes manifest) rather than a
+	// shell header to be followed by module loads and a launcher line.
+	Manifest() bool
+}
+
+var backends = map[string]BatchBackend{}
+
+func registerBackend(b BatchBackend) {
+	backends[b.Name()] = b
+}
+
+func init() {
+	registerBackend(pbsBackend{})
+	registerBackend(slurmBackend{})
+	registerBackend(bareBackend{})
+	registerBackend(lsfBackend{})
+	registerBackend(kubernetesBackend{})
+}
+
+type pbsBackend struct{}
+
+func (pbsBackend) Name() string   { return BatchPBS }
+func (pbsBackend) Detect() bool   { return commandExists("qsub") }
+func (pbsBackend) Manifest() bool { return false }
+
+func (pbsBackend) WriteHeader(builder *strings.Builder, config ExtendedConfig) error {
+	pbsString, err := ExecTemplate(`#!/bin/bash -l
+#PBS -N {{.Name}}
+#PBS -e {{.ErrorFile}}
+#PBS -o {{.OutputFile}}
+#PBS -m abe
+#PBS -M {{.Email}}
+#PBS -l select={{.NumberOfNodes}}`+
+		`:node_type={{.NodeType}}`+
+		`:mpiprocs={{.NumberOfMPIRanksPerNode}}`+
+		`:ompthreads={{.NumberOfOMPThreadsPerProcess}}`+
+		`{{if .NumberOfGPUsPerNode}}:ngpus={{.NumberOfGPUsPerNode}}{{end}}`+`
+{{if .ThreadAffinity}}#PBS -l place={{.ThreadAffinity}}
+{{end}}#PBS -l walltime={{.WalltimeString}}
+{{.ArrayDirective}}`,
+		config,
+	)
+	if err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	builder.WriteString(pbsString)
+	builder.WriteString("\n")
+
+	return nil
+}
+
+func (pbsBackend) SubmitCommand(scriptPath string) []string {
+	return []string{"qsub", scriptPath}
+}
+
+type slurmBackend struct{}
+
+func (slurmBackend) Name() string   { return BatchSlurm }
+func (slurmBackend) Detect() bool   { return commandExists("squeue") }
+func (slurmBackend) Manifest() bool { return false }
+
+func (slurmBackend) WriteHeader(builder *strings.Builder, config ExtendedConfig) error {
+	slurmString, err := ExecTemplate(`#!/bin/bash -l
+#SBATCH -J {{.Name}}
+#SBATCH -o {{.OutputFile}}
+#SBATCH -e {{.ErrorFile}}
+#SBATCH --mail-type=ALL
+#SBATCH --mail-user={{.Email}}
+#SBATCH --nodes {{.NumberOfNodes}}
+#SBATCH --ntasks-per-node {{.NumberOfTasksPerNode}}
+#SBATCH --time={{.WalltimeString}}
+{{if .NumberOfGPUsPerNode}}#SBATCH --gpus-per-node={{.NumberOfGPUsPerNode}}
+{{end}}{{if .ThreadAffinity}}#SBATCH --cpu-bind={{.ThreadAffinity}}
+{{end}}{{.ArrayDirective}}`,
+		config,
+	)
+	if err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	builder.WriteString(slurmString)
+	builder.WriteString("\n")
+
+	return nil
+}
+
+func (slurmBackend) SubmitCommand(scriptPath string) []string {
+	return []string{"sbatch", scriptPath}
+}
+
+type bareBackend struct{}
+
+func (bareBackend) Name() string   { return BatchBare }
+func (bareBackend) Detect() bool   { return commandExists("bash") }
+func (bareBackend) Manifest() bool { return false }
+
+func (bareBackend) WriteHeader(builder *strings.Builder, config ExtendedConfig) error {
+	bareString, err := ExecTemplate(`#!/bin/bash -l
+`,
+		config,
+	)
+	if err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	builder.WriteString(bareString)
+	builder.WriteString("\n")
+
+	return nil
+}
+
+func (bareBackend) SubmitCommand(scriptPath string) []string {
+	return []string{"bash", scriptPath}
+}
+
+type lsfBackend struct{}
+
+func (lsfBackend) Name() string   { return BatchLSF }
+func (lsfBackend) Detect() bool   { return commandExists("bjobs") }
+func (lsfBackend) Manifest() bool { return false }
+
+func (lsfBackend) WriteHeader(builder *strings.Builder, config ExtendedConfig) error {
+	lsfString, err := ExecTemplate(`#!/bin/bash -l
+#BSUB -J {{.Name}}
+#BSUB -o {{.OutputFile}}
+#BSUB -e {{.ErrorFile}}
+#BSUB -n {{.NumberOfMPIRanks}}
+#BSUB -W {{.LSFWalltime}}
+`,
+		struct {
+			ExtendedConfig
+			LSFWalltime string
+		}{
+			ExtendedConfig: config,
+			LSFWalltime:    formatLSFWalltime(config.Walltime),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	builder.WriteString(lsfString)
+	builder.WriteString("\n")
+
+	return nil
+}
+
+func (lsfBackend) SubmitCommand(scriptPath string) []string {
+	return []string{"bsub", scriptPath}
+}
+
+// formatLSFWalltime renders d as bsub -W's [hour:]minute form, e.g. 2h30m
+// becomes "2:30" and 45m becomes "45" (WalltimeString's HH:MM:SS form is not
+// valid input for -W).
+func formatLSFWalltime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := d / time.Hour
+	m := (d - h*time.Hour) / time.Minute
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d", h, m)
+	}
+	return fmt.Sprintf("%d", m)
+}
+
+func commandExists(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
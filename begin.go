@@ -4,32 +4,33 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os/exec"
+	"os"
 	"path"
 	"strings"
 	"text/template"
 	"time"
-
-	"github.com/BurntSushi/toml"
 )
 
 const (
 	BatchPBS         = "pbs"
 	BatchSlurm       = "slurm"
+	BatchLSF         = "lsf"
+	BatchKubernetes  = "kubernetes"
 	BatchBare        = "bare"
 	BatchAutodetect  = "autodetect"
 	BatchUnsupported = "unsupported"
 )
 
+// detectionOrder is the priority in which backends are probed by
+// DetectBatchSystem. BatchBare is last because its Detect always succeeds
+// wherever bash does, making it the fallback.
+var detectionOrder = []string{BatchLSF, BatchPBS, BatchSlurm, BatchKubernetes, BatchBare}
+
 func DetectBatchSystem() string {
-	if _, err := exec.LookPath("qsub"); err == nil {
-		return BatchPBS
-	}
-	if _, err := exec.LookPath("squeue"); err == nil {
-		return BatchSlurm
-	}
-	if _, err := exec.LookPath("bash"); err == nil {
-		return BatchBare
+	for _, name := range detectionOrder {
+		if backend, ok := backends[name]; ok && backend.Detect() {
+			return name
+		}
 	}
 	return BatchUnsupported
 }
@@ -50,11 +51,17 @@ type Config struct {
 	NodeType                     string
 	NumberOfMPIRanksPerNode      int
 	NumberOfOMPThreadsPerProcess int
+	NumberOfGPUsPerNode          int
+	ThreadAffinity               string
+	Launcher                     string
 	Walltime                     time.Duration
 	Email                        string
 	LogDirectory                 string
 	PrintOMPEnvironment          bool
 
+	Array     Array
+	DependsOn []string
+
 	ModulesPreScript []string
 	LoadModules      []string
 
@@ -65,6 +72,7 @@ type Config struct {
 	RunTime    []string
 	Executable string
 	Arguments  []string
+	Container  Container
 
 	PostScript []string
 }
@@ -76,6 +84,8 @@ type ExtendedConfig struct {
 	WalltimeString       string
 	OutputFile           string
 	ErrorFile            string
+	ArrayDirective       string
+	ArrayIndex           string
 }
 
 func NewExtendedConfig(c Config) ExtendedConfig {
@@ -108,85 +118,27 @@ func ExecTemplate(ts string, s interface{}) (string, error) {
 	return builder.String(), nil
 }
 
-func (config Config) writePBSHeader(builder *strings.Builder) error {
-	pbsString, err := ExecTemplate(`#!/bin/bash -l
-#PBS -N {{.Name}}
-#PBS -e {{.ErrorFile}}
-#PBS -o {{.OutputFile}}
-#PBS -m abe
-#PBS -M {{.Email}}
-#PBS -l select={{.NumberOfNodes}}`+
-		`:node_type={{.NodeType}}`+
-		`:mpiprocs={{.NumberOfMPIRanksPerNode}}`+
-		`:ompthreads={{.NumberOfOMPThreadsPerProcess}}`+`
-#PBS -l walltime={{.WalltimeString}}
-`,
-		NewExtendedConfig(config),
-	)
-	if err != nil {
-		return fmt.Errorf("execute template: %w", err)
+func (config Config) JobData(batchSystem string) (string, error) {
+	backend, ok := backends[batchSystem]
+	if !ok {
+		return "", fmt.Errorf("unsupported batch system %q", batchSystem)
 	}
 
-	builder.WriteString(pbsString)
-	builder.WriteString("\n")
-
-	return nil
-}
-
-func (config Config) writeSlurmHeader(builder *strings.Builder) error {
-	pbsString, err := ExecTemplate(`#!/bin/bash -l
-#SBATCH -J {{.Name}}
-#SBATCH -o {{.OutputFile}}
-#SBATCH -e {{.ErrorFile}}
-#SBATCH --mail-type=ALL
-#SBATCH --mail-user={{.Email}}
-#SBATCH --nodes {{.NumberOfNodes}}
-#SBATCH --ntasks-per-node {{.NumberOfTasksPerNode}}
-#SBATCH --time={{.WalltimeString}}
-`,
-		NewExtendedConfig(config),
-	)
+	builder := &strings.Builder{}
+	extended := NewExtendedConfig(config)
+	arrayDirectiveString, err := arrayDirective(batchSystem, config.Array)
 	if err != nil {
-		return fmt.Errorf("execute template: %w", err)
+		return "", err
 	}
+	extended.ArrayDirective = arrayDirectiveString
+	extended.ArrayIndex = arrayIndexVar[batchSystem]
 
-	builder.WriteString(pbsString)
-	builder.WriteString("\n")
-
-	return nil
-}
-
-func (config Config) writeBareHeader(builder *strings.Builder) error {
-	pbsString, err := ExecTemplate(`#!/bin/bash -l
-`,
-		NewExtendedConfig(config),
-	)
-	if err != nil {
-		return fmt.Errorf("execute template: %w", err)
+	if err := backend.WriteHeader(builder, extended); err != nil {
+		return "", fmt.Errorf("write %s header: %w", backend.Name(), err)
 	}
 
-	builder.WriteString(pbsString)
-	builder.WriteString("\n")
-
-	return nil
-}
-
-func (config Config) JobData(batchSystem string) (string, error) {
-	builder := &strings.Builder{}
-
-	switch batchSystem {
-	case BatchPBS:
-		if err := config.writePBSHeader(builder); err != nil {
-			return "", fmt.Errorf("write PBS header: %w", err)
-		}
-	case BatchSlurm:
-		if err := config.writeSlurmHeader(builder); err != nil {
-			return "", fmt.Errorf("write Slurm header: %w", err)
-		}
-	case BatchBare:
-		if err := config.writeBareHeader(builder); err != nil {
-			return "", fmt.Errorf("write bare header: %w", err)
-		}
+	if backend.Manifest() {
+		return builder.String(), nil
 	}
 
 	for _, cmd := range config.ModulesPreScript {
@@ -205,21 +157,24 @@ func (config Config) JobData(batchSystem string) (string, error) {
 	builder.WriteString("\n")
 
 	if config.WorkingDirectory != "" {
-		builder.WriteString("cd " + config.WorkingDirectory + "\n")
+		builder.WriteString("cd " + shellQuote(config.WorkingDirectory) + "\n")
 	}
 	builder.WriteString("\n")
 
 	task := []string{}
 
 	if config.NumberOfMPIRanksPerNode > 0 {
-		mpirunString, err := ExecTemplate("time mpirun -n {{.NumberOfMPIRanks}}",
-			NewExtendedConfig(config),
-		)
+		launcher, err := launcherFor(config, batchSystem)
+		if err != nil {
+			return "", fmt.Errorf("resolve launcher: %w", err)
+		}
+
+		launcherString, err := launcher.Command(extended)
 		if err != nil {
-			return "", fmt.Errorf("create mpirun string: %w", err)
+			return "", fmt.Errorf("create launcher command: %w", err)
 		}
-		if mpirunString != "" {
-			task = append(task, mpirunString)
+		if launcherString != "" {
+			task = append(task, launcherString)
 		}
 	}
 
@@ -227,15 +182,22 @@ func (config Config) JobData(batchSystem string) (string, error) {
 		task = append(task, strings.Join(config.RunTime, " "))
 	}
 
-	task = append(task, config.Executable)
-
-	if len(config.Arguments) > 0 {
-		task = append(task, strings.Join(config.Arguments, " "))
+	containerString, err := containerCommand(extended)
+	if err != nil {
+		return "", fmt.Errorf("create container command: %w", err)
+	}
+	if containerString != "" {
+		task = append(task, containerString)
+	} else {
+		task = append(task, config.Executable)
+		if len(config.Arguments) > 0 {
+			task = append(task, strings.Join(config.Arguments, " "))
+		}
 	}
 
 	rawTaskString := strings.Join(task, " ")
 
-	taskStringResult, err := ExecTemplate(rawTaskString, NewExtendedConfig(config))
+	taskStringResult, err := ExecTemplate(rawTaskString, extended)
 	if err != nil {
 		return "", fmt.Errorf("exec task string template: %w", err)
 	}
@@ -250,31 +212,56 @@ func (config Config) JobData(batchSystem string) (string, error) {
 	return builder.String(), nil
 }
 
-func run() error {
-	batchSystem := flag.String("b", BatchAutodetect, "Batch system to use [pbs, slurm], or default to autodetect")
-	flag.Parse()
+// stringListFlag accumulates repeated occurrences of a flag, e.g.
+// -profile a -profile b.
+type stringListFlag []string
+
+func (s *stringListFlag) String() string { return strings.Join(*s, ",") }
 
-	if len(flag.Args()) == 0 {
-		log.Fatal("Job file is not specified")
+func (s *stringListFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// parseGenerateArgs parses the flags shared by the default script-generating
+// mode and the submit subcommand, returning the batch system, the job file, any -profile
+// overlays to layer on top of it, and the dependency type to use for
+// -dep-type (only meaningful to submit).
+func parseGenerateArgs(args []string) (batchSystem string, filename string, profiles []string, depType string, err error) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	b := fs.String("b", BatchAutodetect, "Batch system to use [pbs, slurm, lsf, kubernetes, bare], or default to autodetect")
+	dep := fs.String("dep-type", "afterok", "Dependency type for DependsOn jobs [afterok, afterany, afternotok]")
+	var profileFlag stringListFlag
+	fs.Var(&profileFlag, "profile", "Profile overlay to apply: a [profiles.<name>] block in the job file, or a path to an overlay TOML file; may be repeated")
+	fs.Parse(args)
+
+	if len(fs.Args()) == 0 {
+		return "", "", nil, "", fmt.Errorf("job file is not specified")
 	}
-	filename := flag.Args()[0]
 
-	config := Config{}
+	return *b, fs.Args()[0], []string(profileFlag), *dep, nil
+}
+
+func runGenerate(args []string) error {
+	batchSystem, filename, profiles, _, err := parseGenerateArgs(args)
+	if err != nil {
+		return err
+	}
 
-	_, err := toml.DecodeFile(filename, &config)
+	config, err := loadLayeredConfig(filename, profiles)
 	if err != nil {
-		return fmt.Errorf("decode file: %w", err)
+		return err
 	}
 
-	if *batchSystem == BatchAutodetect {
-		*batchSystem = DetectBatchSystem()
+	if batchSystem == BatchAutodetect {
+		batchSystem = DetectBatchSystem()
 	}
 
-	if *batchSystem == BatchUnsupported {
+	if batchSystem == BatchUnsupported {
 		return fmt.Errorf("unsupported platform")
 	}
 
-	jobData, err := config.JobData(*batchSystem)
+	jobData, err := config.JobData(batchSystem)
 	if err != nil {
 		return fmt.Errorf("getting job data: %w", err)
 	}
@@ -283,6 +270,26 @@ func run() error {
 	return nil
 }
 
+// run dispatches to the submit/status/cancel/logs lifecycle subcommands,
+// falling back to the original script-generating behavior for anything
+// else so that `begin job.toml` keeps working unchanged.
+func run() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "submit":
+			return runSubmit(os.Args[2:])
+		case "status":
+			return runStatus(os.Args[2:])
+		case "cancel":
+			return runCancel(os.Args[2:])
+		case "logs":
+			return runLogs(os.Args[2:])
+		}
+	}
+
+	return runGenerate(os.Args[1:])
+}
+
 func main() {
 	if err := run(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os/exec"
+)
+
+func runCancel(args []string) error {
+	fs := flag.NewFlagSet("cancel", flag.ExitOnError)
+	fs.Parse(args)
+
+	if len(fs.Args()) == 0 {
+		return fmt.Errorf("job name is not specified")
+	}
+	name := fs.Args()[0]
+
+	store, err := loadJobStore()
+	if err != nil {
+		return err
+	}
+	record, err := store.get(name)
+	if err != nil {
+		return err
+	}
+
+	if err := cancelJob(record); err != nil {
+		return fmt.Errorf("cancel job: %w", err)
+	}
+
+	fmt.Printf("cancelled %s (job %s)\n", record.Name, record.JobID)
+
+	return nil
+}
+
+func cancelJob(record JobRecord) error {
+	var cmd *exec.Cmd
+	switch record.BatchSystem {
+	case BatchPBS:
+		cmd = exec.Command("qdel", record.JobID)
+	case BatchSlurm:
+		cmd = exec.Command("scancel", record.JobID)
+	case BatchLSF:
+		cmd = exec.Command("bkill", record.JobID)
+	case BatchKubernetes:
+		cmd = exec.Command("kubectl", "delete", "job", record.JobID)
+	case BatchBare:
+		cmd = exec.Command("kill", record.JobID)
+	default:
+		return fmt.Errorf("cancel not supported for batch system %q", record.BatchSystem)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("run %s: %w: %s", cmd.Path, err, string(out))
+	}
+
+	return nil
+}
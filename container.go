@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	ContainerSingularity = "singularity"
+	ContainerApptainer   = "apptainer"
+	ContainerPodman      = "podman"
+)
+
+// Container wraps the executable invocation in a container runtime call,
+// letting one job TOML target both bare-metal modules and container images.
+type Container struct {
+	Runtime  string
+	Image    string
+	Binds    []string
+	Env      map[string]string
+	Nvidia   bool
+	Writable bool
+}
+
+// containerCommand builds the runtime invocation wrapping config.Executable
+// and config.Arguments, or "" if no container is configured.
+func containerCommand(config ExtendedConfig) (string, error) {
+	c := config.Container
+	if c.Runtime == "" {
+		return "", nil
+	}
+	if c.Image == "" {
+		return "", fmt.Errorf("container runtime %q requires an Image", c.Runtime)
+	}
+
+	command := append([]string{config.Executable}, config.Arguments...)
+
+	switch c.Runtime {
+	case ContainerSingularity, ContainerApptainer:
+		return singularityCommand(c, command), nil
+	case ContainerPodman:
+		return podmanCommand(c, config.WorkingDirectory, command), nil
+	default:
+		return "", fmt.Errorf("unknown container runtime %q", c.Runtime)
+	}
+}
+
+func singularityCommand(c Container, command []string) string {
+	args := []string{c.Runtime, "exec"}
+	if c.Nvidia {
+		args = append(args, "--nv")
+	}
+	if c.Writable {
+		args = append(args, "--writable")
+	}
+	for _, bind := range c.Binds {
+		args = append(args, "-B", shellQuote(bind))
+	}
+	for _, key := range sortedKeys(c.Env) {
+		args = append(args, "--env", fmt.Sprintf("%s=%s", key, shellQuote(c.Env[key])))
+	}
+	args = append(args, shellQuote(c.Image))
+	args = append(args, command...)
+
+	return strings.Join(args, " ")
+}
+
+func podmanCommand(c Container, workingDirectory string, command []string) string {
+	args := []string{"podman", "run", "--rm", "--userns=keep-id"}
+	if c.Nvidia {
+		args = append(args, "--device", "nvidia.com/gpu=all")
+	}
+	if !c.Writable {
+		args = append(args, "--read-only")
+	}
+	if workingDirectory != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:%s", shellQuote(workingDirectory), shellQuote(workingDirectory)), "-w", shellQuote(workingDirectory))
+	}
+	for _, bind := range c.Binds {
+		args = append(args, "-v", shellQuote(bind))
+	}
+	for _, key := range sortedKeys(c.Env) {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", key, shellQuote(c.Env[key])))
+	}
+	args = append(args, shellQuote(c.Image))
+	args = append(args, command...)
+
+	return strings.Join(args, " ")
+}
+
+// shellQuote wraps value in single quotes unconditionally, so it survives
+// word-splitting and expansion when the generated script line is run by
+// bash, regardless of whether it contains whitespace, '$', ';', '&', or a
+// bare single quote.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
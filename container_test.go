@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "foo", "'foo'"},
+		{"space", "my job dir", "'my job dir'"},
+		{"single quote", "it's", `'it'\''s'`},
+		{"metacharacters", "$FOO;bar`baz`", "'$FOO;bar`baz`'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := shellQuote(c.value); got != c.want {
+				t.Errorf("shellQuote(%q) = %q, want %q", c.value, got, c.want)
+			}
+		})
+	}
+}
+
+func TestPodmanCommandQuotesWorkingDirectoryAndBinds(t *testing.T) {
+	c := Container{
+		Image: "my image",
+		Binds: []string{"/data dir:/data"},
+	}
+
+	got := podmanCommand(c, "/scratch/my job dir", []string{"./app"})
+
+	want := "podman run --rm --userns=keep-id --read-only " +
+		"-v '/scratch/my job dir':'/scratch/my job dir' -w '/scratch/my job dir' " +
+		"-v '/data dir:/data' 'my image' ./app"
+	if got != want {
+		t.Errorf("podmanCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestSingularityCommandQuotesImage(t *testing.T) {
+	c := Container{
+		Runtime: ContainerSingularity,
+		Image:   "my image.sif",
+	}
+
+	got := singularityCommand(c, []string{"./app"})
+
+	want := "singularity exec 'my image.sif' ./app"
+	if got != want {
+		t.Errorf("singularityCommand() = %q, want %q", got, want)
+	}
+}
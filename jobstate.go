@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// JobRecord is a single tracked job, persisted across invocations so that
+// status/cancel/logs can find it again by Name.
+type JobRecord struct {
+	Name        string
+	JobID       string
+	BatchSystem string
+	ScriptPath  string
+	OutputFile  string
+	ErrorFile   string
+	SubmittedAt time.Time
+}
+
+// JobStore is the on-disk representation of ~/.begin/jobs.toml.
+type JobStore struct {
+	Jobs map[string]JobRecord
+}
+
+func jobStorePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home directory: %w", err)
+	}
+	return filepath.Join(home, ".begin", "jobs.toml"), nil
+}
+
+func loadJobStore() (JobStore, error) {
+	store := JobStore{Jobs: map[string]JobRecord{}}
+
+	path, err := jobStorePath()
+	if err != nil {
+		return store, err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return store, nil
+	}
+
+	if _, err := toml.DecodeFile(path, &store); err != nil {
+		return store, fmt.Errorf("decode job store: %w", err)
+	}
+	if store.Jobs == nil {
+		store.Jobs = map[string]JobRecord{}
+	}
+
+	return store, nil
+}
+
+func (store JobStore) save() error {
+	path, err := jobStorePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create job store: %w", err)
+	}
+	defer f.Close()
+
+	if err := toml.NewEncoder(f).Encode(store); err != nil {
+		return fmt.Errorf("encode job store: %w", err)
+	}
+
+	return nil
+}
+
+func (store JobStore) put(record JobRecord) error {
+	store.Jobs[record.Name] = record
+	return store.save()
+}
+
+func (store JobStore) get(name string) (JobRecord, error) {
+	record, ok := store.Jobs[name]
+	if !ok {
+		return JobRecord{}, fmt.Errorf("no tracked job named %q", name)
+	}
+	return record, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kubernetesBackend emits a batch/v1 Job manifest instead of a shell script
+// header, for facilities that schedule via Kubernetes rather than a
+// traditional HPC batch system. Config.Executable is taken as the container
+// image, and Config.Arguments as the command run inside it.
+type kubernetesBackend struct{}
+
+func (kubernetesBackend) Name() string   { return BatchKubernetes }
+func (kubernetesBackend) Detect() bool   { return commandExists("kubectl") }
+func (kubernetesBackend) Manifest() bool { return true }
+
+func (kubernetesBackend) WriteHeader(builder *strings.Builder, config ExtendedConfig) error {
+	command := make([]string, len(config.Arguments))
+	for i, arg := range config.Arguments {
+		command[i] = fmt.Sprintf("%q", arg)
+	}
+
+	manifest, err := ExecTemplate(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{.Name}}
+spec:
+  parallelism: {{.NumberOfNodes}}
+  completions: {{.NumberOfNodes}}
+  activeDeadlineSeconds: {{.WalltimeSeconds}}
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: {{.Name}}
+          image: {{.Executable}}
+          command: [{{.Command}}]
+          resources:
+            requests:
+              cpu: "{{.NumberOfOMPThreadsPerProcess}}"
+`,
+		struct {
+			ExtendedConfig
+			Command         string
+			WalltimeSeconds int64
+		}{
+			ExtendedConfig:  config,
+			Command:         strings.Join(command, ", "),
+			WalltimeSeconds: int64(config.Walltime.Seconds()),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	builder.WriteString(manifest)
+
+	return nil
+}
+
+func (kubernetesBackend) SubmitCommand(scriptPath string) []string {
+	return []string{"kubectl", "apply", "-f", scriptPath}
+}
@@ -0,0 +1,131 @@
+package main
+
+import "fmt"
+
+const (
+	LauncherMPIRun  = "mpirun"
+	LauncherSrun    = "srun"
+	LauncherJsrun   = "jsrun"
+	LauncherAprun   = "aprun"
+	LauncherMPIExec = "mpiexec"
+	LauncherNone    = "none"
+)
+
+// Launcher emits the command-line invocation that runs an MPI job under a
+// particular launcher, given the job's rank/thread/GPU topology.
+type Launcher interface {
+	Name() string
+	Command(config ExtendedConfig) (string, error)
+}
+
+var launchers = map[string]Launcher{}
+
+func registerLauncher(l Launcher) {
+	launchers[l.Name()] = l
+}
+
+func init() {
+	registerLauncher(mpirunLauncher{})
+	registerLauncher(srunLauncher{})
+	registerLauncher(jsrunLauncher{})
+	registerLauncher(aprunLauncher{})
+	registerLauncher(mpiexecLauncher{})
+	registerLauncher(noneLauncher{})
+}
+
+// defaultLauncher is the launcher a backend uses when Config.Launcher is
+// left unset.
+var defaultLauncher = map[string]string{
+	BatchSlurm:      LauncherSrun,
+	BatchPBS:        LauncherMPIRun,
+	BatchLSF:        LauncherJsrun,
+	BatchBare:       LauncherMPIRun,
+	BatchKubernetes: LauncherNone,
+}
+
+// launcherFor resolves the Launcher to use for config on batchSystem,
+// honoring an explicit Config.Launcher, then the backend's defaultLauncher
+// entry, and falling back to mpirun.
+func launcherFor(config Config, batchSystem string) (Launcher, error) {
+	name := config.Launcher
+	if name == "" {
+		name = defaultLauncher[batchSystem]
+	}
+	if name == "" {
+		name = LauncherMPIRun
+	}
+
+	launcher, ok := launchers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown launcher %q", name)
+	}
+	return launcher, nil
+}
+
+type mpirunLauncher struct{}
+
+func (mpirunLauncher) Name() string { return LauncherMPIRun }
+
+func (mpirunLauncher) Command(config ExtendedConfig) (string, error) {
+	return ExecTemplate("time mpirun -n {{.NumberOfMPIRanks}}", config)
+}
+
+type srunLauncher struct{}
+
+func (srunLauncher) Name() string { return LauncherSrun }
+
+func (srunLauncher) Command(config ExtendedConfig) (string, error) {
+	command, err := ExecTemplate(`time srun -n {{.NumberOfMPIRanks}} --cpus-per-task={{.NumberOfOMPThreadsPerProcess}}`+
+		`{{if .NumberOfGPUsPerNode}} --gpus-per-node={{.NumberOfGPUsPerNode}}{{end}}`+
+		`{{if .ThreadAffinity}} --cpu-bind={{.ThreadAffinity}}{{end}}`,
+		config,
+	)
+	if err != nil {
+		return "", err
+	}
+	return command, nil
+}
+
+type jsrunLauncher struct{}
+
+func (jsrunLauncher) Name() string { return LauncherJsrun }
+
+func (jsrunLauncher) Command(config ExtendedConfig) (string, error) {
+	command, err := ExecTemplate(`time jsrun -n {{.NumberOfNodes}} -a {{.NumberOfMPIRanksPerNode}} -c ALL_CPUS`+
+		`{{if .NumberOfGPUsPerNode}} -g {{.NumberOfGPUsPerNode}}{{end}}`+
+		`{{if .ThreadAffinity}} -b {{.ThreadAffinity}}{{end}}`,
+		config,
+	)
+	if err != nil {
+		return "", err
+	}
+	return command, nil
+}
+
+type aprunLauncher struct{}
+
+func (aprunLauncher) Name() string { return LauncherAprun }
+
+func (aprunLauncher) Command(config ExtendedConfig) (string, error) {
+	command, err := ExecTemplate(`time aprun -n {{.NumberOfMPIRanks}} -N {{.NumberOfMPIRanksPerNode}} -d {{.NumberOfOMPThreadsPerProcess}}`+
+		`{{if .ThreadAffinity}} -cc {{.ThreadAffinity}}{{end}}`,
+		config,
+	)
+	if err != nil {
+		return "", err
+	}
+	return command, nil
+}
+
+type mpiexecLauncher struct{}
+
+func (mpiexecLauncher) Name() string { return LauncherMPIExec }
+
+func (mpiexecLauncher) Command(config ExtendedConfig) (string, error) {
+	return ExecTemplate("time mpiexec -n {{.NumberOfMPIRanks}}", config)
+}
+
+type noneLauncher struct{}
+
+func (noneLauncher) Name() string                                  { return LauncherNone }
+func (noneLauncher) Command(config ExtendedConfig) (string, error) { return "", nil }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func runLogs(args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	errOnly := fs.Bool("e", false, "tail the error file instead of the output file")
+	fs.Parse(args)
+
+	if len(fs.Args()) == 0 {
+		return fmt.Errorf("job name is not specified")
+	}
+	name := fs.Args()[0]
+
+	store, err := loadJobStore()
+	if err != nil {
+		return err
+	}
+	record, err := store.get(name)
+	if err != nil {
+		return err
+	}
+
+	file := record.OutputFile
+	if *errOnly {
+		file = record.ErrorFile
+	}
+
+	cmd := exec.Command("tail", "-f", file)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+	return cmd.Run()
+}
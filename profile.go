@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// attrMap is the generic, untyped representation of a job file used while
+// layering profile overlays on top of a base Config.
+type attrMap map[string]interface{}
+
+func loadAttrMap(filename string) (attrMap, error) {
+	m := attrMap{}
+	if _, err := toml.DecodeFile(filename, &m); err != nil {
+		return nil, fmt.Errorf("decode file: %w", err)
+	}
+	return m, nil
+}
+
+// mergeAttrMap deep-merges src into dst, with src winning on conflicts.
+// Nested tables are merged key by key; scalars and arrays are replaced
+// wholesale.
+func mergeAttrMap(dst, src attrMap) attrMap {
+	for k, v := range src {
+		if srcTable, ok := v.(map[string]interface{}); ok {
+			if dstTable, ok := dst[k].(map[string]interface{}); ok {
+				dst[k] = map[string]interface{}(mergeAttrMap(dstTable, srcTable))
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// resolveProfile returns the overlay attrMap for a "-profile name" argument,
+// first checking the base file's own [profiles.<name>] table, then falling
+// back to treating name as the path to a standalone overlay TOML file.
+func resolveProfile(base attrMap, name string) (attrMap, error) {
+	if profiles, ok := base["profiles"].(map[string]interface{}); ok {
+		if profile, ok := profiles[name].(map[string]interface{}); ok {
+			return attrMap(profile), nil
+		}
+	}
+	return loadAttrMap(name)
+}
+
+// applyOverrideEnv merges the BEGIN_OVERRIDE_JSON environment variable, if
+// set, on top of attrs.
+func applyOverrideEnv(attrs attrMap) (attrMap, error) {
+	raw := os.Getenv("BEGIN_OVERRIDE_JSON")
+	if raw == "" {
+		return attrs, nil
+	}
+
+	override := attrMap{}
+	if err := json.Unmarshal([]byte(raw), &override); err != nil {
+		return nil, fmt.Errorf("decode BEGIN_OVERRIDE_JSON: %w", err)
+	}
+
+	return mergeAttrMap(attrs, override), nil
+}
+
+// taskTemplateFields are the top-level Config fields JobData itself templates
+// a second time against ExtendedConfig (exposing ArrayIndex, NumberOfMPIRanks,
+// etc.). templateAttrMap leaves them untouched so a job file can write
+// Executable = "./app {{.ArrayIndex}}" without this first pass consuming the
+// placeholder before ExtendedConfig exists to fill it in.
+var taskTemplateFields = map[string]bool{
+	"Executable": true,
+	"Arguments":  true,
+	"RunTime":    true,
+}
+
+// templateAttrMap runs a first templating pass over every string value in
+// attrs, resolving references like "{{.Cluster}}" against attrs itself, so
+// a profile overlay setting Cluster can feed NodeType = "{{.Cluster}}-fat".
+func templateAttrMap(attrs attrMap) (attrMap, error) {
+	result := attrMap{}
+	for k, v := range attrs {
+		if taskTemplateFields[k] {
+			result[k] = v
+			continue
+		}
+		rendered, err := templateAttrValue(k, v, attrs)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = rendered
+	}
+	return result, nil
+}
+
+func templateAttrValue(key string, v interface{}, attrs attrMap) (interface{}, error) {
+	switch value := v.(type) {
+	case string:
+		rendered, err := ExecTemplate(value, attrs)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", key, err)
+		}
+		return rendered, nil
+	case map[string]interface{}:
+		nested, err := templateAttrMap(attrMap(value))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}(nested), nil
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, item := range value {
+			out, err := templateAttrValue(fmt.Sprintf("%s[%d]", key, i), item, attrs)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = out
+		}
+		return rendered, nil
+	default:
+		return v, nil
+	}
+}
+
+// loadLayeredConfig loads filename as the base job file, applies each
+// -profile overlay in order, merges BEGIN_OVERRIDE_JSON, runs the
+// templating pass, and decodes the result into a Config.
+func loadLayeredConfig(filename string, profiles []string) (Config, error) {
+	attrs, err := loadAttrMap(filename)
+	if err != nil {
+		return Config{}, err
+	}
+
+	for _, name := range profiles {
+		overlay, err := resolveProfile(attrs, name)
+		if err != nil {
+			return Config{}, fmt.Errorf("resolve profile %q: %w", name, err)
+		}
+		attrs = mergeAttrMap(attrs, overlay)
+	}
+
+	attrs, err = applyOverrideEnv(attrs)
+	if err != nil {
+		return Config{}, err
+	}
+
+	attrs, err = templateAttrMap(attrs)
+	if err != nil {
+		return Config{}, fmt.Errorf("template attributes: %w", err)
+	}
+
+	delete(attrs, "profiles")
+
+	var buf strings.Builder
+	if err := toml.NewEncoder(&buf).Encode(map[string]interface{}(attrs)); err != nil {
+		return Config{}, fmt.Errorf("re-encode merged attributes: %w", err)
+	}
+
+	config := Config{}
+	if _, err := toml.Decode(buf.String(), &config); err != nil {
+		return Config{}, fmt.Errorf("decode merged config: %w", err)
+	}
+
+	return config, nil
+}
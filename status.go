@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	fs.Parse(args)
+
+	if len(fs.Args()) == 0 {
+		return fmt.Errorf("job name is not specified")
+	}
+	name := fs.Args()[0]
+
+	store, err := loadJobStore()
+	if err != nil {
+		return err
+	}
+	record, err := store.get(name)
+	if err != nil {
+		return err
+	}
+
+	state, err := jobState(record)
+	if err != nil {
+		return fmt.Errorf("get job state: %w", err)
+	}
+
+	fmt.Printf("%s\t%s\t%s\n", record.Name, record.JobID, state)
+
+	return nil
+}
+
+// jobState asks the batch system (or the OS, for bare jobs) for the current
+// state of a tracked job.
+func jobState(record JobRecord) (string, error) {
+	switch record.BatchSystem {
+	case BatchPBS:
+		out, err := exec.Command("qstat", "-f", record.JobID).CombinedOutput()
+		if err != nil {
+			return "finished", nil
+		}
+		return parsePBSState(string(out)), nil
+	case BatchSlurm:
+		out, err := exec.Command("squeue", "-j", record.JobID, "-h", "-o", "%T").CombinedOutput()
+		if err != nil || strings.TrimSpace(string(out)) == "" {
+			return "finished", nil
+		}
+		return strings.TrimSpace(string(out)), nil
+	case BatchLSF:
+		out, err := exec.Command("bjobs", "-noheader", "-o", "stat", record.JobID).CombinedOutput()
+		if err != nil || strings.TrimSpace(string(out)) == "" {
+			return "finished", nil
+		}
+		return strings.TrimSpace(string(out)), nil
+	case BatchKubernetes:
+		out, err := exec.Command("kubectl", "get", "job", record.JobID, "-o", "jsonpath={.status.conditions[0].type}").CombinedOutput()
+		if err != nil || strings.TrimSpace(string(out)) == "" {
+			return "running", nil
+		}
+		return strings.TrimSpace(string(out)), nil
+	case BatchBare:
+		return barePIDState(record.JobID), nil
+	default:
+		return "", fmt.Errorf("status not supported for batch system %q", record.BatchSystem)
+	}
+}
+
+func parsePBSState(qstatOutput string) string {
+	for _, line := range strings.Split(qstatOutput, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "job_state") {
+			fields := strings.SplitN(line, "=", 2)
+			if len(fields) == 2 {
+				return strings.TrimSpace(fields[1])
+			}
+		}
+	}
+	return "unknown"
+}
+
+func barePIDState(pid string) string {
+	if err := exec.Command("kill", "-0", pid).Run(); err != nil {
+		return "finished"
+	}
+	return "running"
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var slurmJobIDPattern = regexp.MustCompile(`\d+`)
+
+// submitScript writes jobData to a temp file and hands it to the batch
+// system's submission command, returning the job ID it reports. depArgs,
+// if non-empty, are spliced in front of the script path argument (e.g.
+// "-W depend=afterok:123" for PBS).
+func submitScript(config Config, batchSystem string, jobData string, depArgs []string) (jobID string, scriptPath string, err error) {
+	f, err := os.CreateTemp("", config.Name+"-*.sh")
+	if err != nil {
+		return "", "", fmt.Errorf("create script file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(jobData); err != nil {
+		return "", "", fmt.Errorf("write script file: %w", err)
+	}
+	scriptPath = f.Name()
+
+	backend, ok := backends[batchSystem]
+	if !ok {
+		return "", scriptPath, fmt.Errorf("submission not supported for batch system %q", batchSystem)
+	}
+
+	argv := backend.SubmitCommand(scriptPath)
+	if len(depArgs) > 0 {
+		argv = append(argv[:len(argv)-1:len(argv)-1], append(depArgs, scriptPath)...)
+	}
+
+	switch batchSystem {
+	case BatchBare:
+		jobID, err = submitBare(scriptPath)
+	case BatchKubernetes:
+		// The Job's name is its identifier; there is no separate job ID.
+		_, err = runSubmitCommand(argv)
+		jobID = config.Name
+	case BatchSlurm:
+		var out string
+		out, err = runSubmitCommand(argv)
+		jobID = slurmJobIDPattern.FindString(out)
+	default:
+		jobID, err = runSubmitCommand(argv)
+		jobID = strings.TrimSpace(jobID)
+	}
+	if err != nil {
+		return "", scriptPath, err
+	}
+
+	return jobID, scriptPath, nil
+}
+
+// dependencyArgs resolves config.DependsOn job names against the local job
+// store and returns the extra submit-command arguments that express the
+// dependency, or nil if there's nothing to depend on.
+func dependencyArgs(batchSystem, depType string, dependsOn []string) ([]string, error) {
+	if len(dependsOn) == 0 {
+		return nil, nil
+	}
+
+	store, err := loadJobStore()
+	if err != nil {
+		return nil, err
+	}
+
+	jobIDs := make([]string, len(dependsOn))
+	for i, name := range dependsOn {
+		record, err := store.get(name)
+		if err != nil {
+			return nil, fmt.Errorf("resolve dependency %q: %w", name, err)
+		}
+		jobIDs[i] = record.JobID
+	}
+
+	switch batchSystem {
+	case BatchPBS:
+		return []string{"-W", fmt.Sprintf("depend=%s:%s", depType, strings.Join(jobIDs, ":"))}, nil
+	case BatchSlurm:
+		return []string{fmt.Sprintf("--dependency=%s:%s", depType, strings.Join(jobIDs, ":"))}, nil
+	default:
+		return nil, fmt.Errorf("dependencies not supported for batch system %q", batchSystem)
+	}
+}
+
+func runSubmitCommand(argv []string) (string, error) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("run %s: %w: %s", argv[0], err, string(out))
+	}
+	return string(out), nil
+}
+
+// submitBare runs the script in the background with bash and returns its PID.
+func submitBare(scriptPath string) (string, error) {
+	if err := os.Chmod(scriptPath, 0o755); err != nil {
+		return "", fmt.Errorf("chmod script: %w", err)
+	}
+
+	cmd := exec.Command("bash", scriptPath)
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("start script: %w", err)
+	}
+
+	pid := cmd.Process.Pid
+	go cmd.Wait()
+
+	return strconv.Itoa(pid), nil
+}
+
+func runSubmit(args []string) error {
+	batchSystem, filename, profiles, depType, err := parseGenerateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	config, err := loadLayeredConfig(filename, profiles)
+	if err != nil {
+		return err
+	}
+
+	if batchSystem == BatchAutodetect {
+		batchSystem = DetectBatchSystem()
+	}
+	if batchSystem == BatchUnsupported {
+		return fmt.Errorf("unsupported platform")
+	}
+
+	jobData, err := config.JobData(batchSystem)
+	if err != nil {
+		return fmt.Errorf("getting job data: %w", err)
+	}
+
+	depArgs, err := dependencyArgs(batchSystem, depType, config.DependsOn)
+	if err != nil {
+		return fmt.Errorf("resolve dependencies: %w", err)
+	}
+
+	jobID, scriptPath, err := submitScript(config, batchSystem, jobData, depArgs)
+	if err != nil {
+		return fmt.Errorf("submit job: %w", err)
+	}
+
+	extended := NewExtendedConfig(config)
+	store, err := loadJobStore()
+	if err != nil {
+		return err
+	}
+	if err := store.put(JobRecord{
+		Name:        config.Name,
+		JobID:       jobID,
+		BatchSystem: batchSystem,
+		ScriptPath:  scriptPath,
+		OutputFile:  extended.OutputFile,
+		ErrorFile:   extended.ErrorFile,
+		SubmittedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("persist job record: %w", err)
+	}
+
+	fmt.Printf("submitted %s as job %s\n", config.Name, jobID)
+
+	return nil
+}